@@ -6,7 +6,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
@@ -14,22 +17,63 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/linter"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-pipeline/pkg/lint/policy"
 	"github.com/jenkins-x/lighthouse/pkg/config/job"
 	"github.com/jenkins-x/lighthouse/pkg/triggerconfig"
 	"github.com/jenkins-x/lighthouse/pkg/triggerconfig/inrepo"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	tektonconfig "github.com/tektoncd/pipeline/pkg/apis/config"
+	"gopkg.in/yaml.v3"
 )
 
+// FormatSarif outputs lint failures as a SARIF 2.1.0 report, see WriteSarifReport
+const FormatSarif = "sarif"
+
+// sourceLocation is the line/column a pipeline source file's root YAML node started at,
+// used to annotate SARIF results with something more useful than a bare error message.
+type sourceLocation struct {
+	Line   int
+	Column int
+}
+
 // Options contains the command line options
 type Options struct {
 	linter.Options
 
-	Dir       string
-	Namespace string
-	OutFile   string
-	Format    string
-	Recursive bool
+	Dir         string
+	Namespace   string
+	OutFile     string
+	Format      string
+	Recursive   bool
+	Concurrency int
+	Timeout     time.Duration
+	PolicyDir   string
+
+	// sourceLocations tracks the yaml.Node position that each loaded source file's root
+	// node started at, keyed by the same File path recorded on the linter.Test.
+	sourceLocations map[string]sourceLocation
+
+	// jobs accumulates the expensive per-source-file validations discovered while walking
+	// triggers.yaml files, so they can be run on a bounded worker pool instead of in-line.
+	jobs []*lintJob
+
+	// policyEvaluator is non-nil when --policy-dir is set, evaluating every triggerconfig.Config
+	// and resolved PipelineRun against the Rego policies loaded from that directory.
+	policyEvaluator *policy.Evaluator
+}
+
+// lintJob is a single SourcePath validation discovered while walking a triggers.yaml,
+// dispatched onto the worker pool once discovery of all jobs has finished.
+type lintJob struct {
+	test *linter.Test
+	path string
+
+	// policyTests collects any policy violations found while validating path, so they can be
+	// flattened into o.Tests in job discovery order once the whole worker pool has finished -
+	// appending them directly from the worker goroutine would make their order (and so the
+	// order of SARIF/terminal output) depend on which job happens to finish first.
+	policyTests []*linter.Test
 }
 
 var (
@@ -62,18 +106,45 @@ func NewCmdPipelineLint() (*cobra.Command, *Options) {
 	}
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "The directory to look for the .lighthouse folder")
 	cmd.Flags().BoolVarP(&o.Recursive, "recursive", "r", false, "Recurisvely find all '.lighthouse' folders such as if linting a Pipeline Catalog")
+	cmd.Flags().StringVarP(&o.Format, "format", "", "", "The output format to use. If not specified the results are printed to the terminal. Supported values: 'sarif'")
+	cmd.Flags().StringVarP(&o.OutFile, "out", "", "", "The file to write the report to when --format is specified")
+	cmd.Flags().IntVarP(&o.Concurrency, "concurrency", "c", runtime.NumCPU(), "The number of source files to validate concurrently")
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", time.Minute, "The maximum time to spend validating a single source file")
+	cmd.Flags().StringVarP(&o.PolicyDir, "policy-dir", "", "", "The directory of Rego policies to evaluate trigger configs and PipelineRuns against, see pkg/lint/policy/builtin for a starter ruleset")
 
 	o.Options.AddFlags(cmd)
 
 	return cmd, o
 }
 
+// GetContext returns the context used to validate PipelineRuns, preconfigured with Tekton's
+// `enable-api-fields: alpha` feature flag so embedded Pipeline/Task specs that rely on
+// propagated workspaces/params - rather than redeclaring them - validate as Tekton itself
+// would run them, instead of failing lint with a false-positive "undeclared" error.
+func (o *Options) GetContext() context.Context {
+	ctx := o.Options.GetContext()
+	return tektonconfig.ToContext(ctx, &tektonconfig.Config{
+		FeatureFlags: &tektonconfig.FeatureFlags{
+			EnableAPIFields: "alpha",
+		},
+	})
+}
+
 // Run implements this command
 func (o *Options) Run() error {
 	err := o.Options.Validate()
 	if err != nil {
 		return errors.Wrapf(err, "failed to validate options")
 	}
+	o.sourceLocations = map[string]sourceLocation{}
+
+	if o.PolicyDir != "" {
+		evaluator, err := policy.NewEvaluator(o.GetContext(), o.PolicyDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load policies from %s", o.PolicyDir)
+		}
+		o.policyEvaluator = evaluator
+	}
 
 	if o.Recursive {
 		err := filepath.Walk(o.Dir, func(path string, info os.FileInfo, err error) error {
@@ -96,7 +167,17 @@ func (o *Options) Run() error {
 		}
 	}
 
-	return o.LogResults()
+	o.runJobs()
+
+	switch o.Format {
+	case FormatSarif:
+		if o.OutFile == "" {
+			return errors.Errorf("--out is required when --format %s is specified", FormatSarif)
+		}
+		return o.WriteSarifReport(o.Tests, o.OutFile)
+	default:
+		return o.LogResults()
+	}
 }
 
 func (o *Options) LintDir(dir string) error {
@@ -124,12 +205,14 @@ func (o *Options) LintDir(dir string) error {
 			File: triggersFile,
 		}
 		o.Tests = append(o.Tests, test)
+		o.recordSourceLocation(triggersFile)
 		triggers := &triggerconfig.Config{}
 		err = yamls.LoadFile(triggersFile, triggers)
 		if err != nil {
 			test.Error = err
 			continue
 		}
+		o.Tests = append(o.Tests, o.evaluatePolicy(o.GetContext(), triggersFile, triggers)...)
 
 		o.loadConfigFile(triggers, triggerDir)
 	}
@@ -137,7 +220,6 @@ func (o *Options) LintDir(dir string) error {
 }
 
 func (o *Options) loadConfigFile(repoConfig *triggerconfig.Config, dir string) *triggerconfig.Config {
-	ctx := o.GetContext()
 	for i := range repoConfig.Spec.Presubmits {
 		r := &repoConfig.Spec.Presubmits[i]
 		if r.SourcePath != "" {
@@ -146,10 +228,8 @@ func (o *Options) loadConfigFile(repoConfig *triggerconfig.Config, dir string) *
 				File: path,
 			}
 			o.Tests = append(o.Tests, test)
-			err := loadJobBaseFromSourcePath(ctx, path)
-			if err != nil {
-				test.Error = err
-			}
+			o.recordSourceLocation(path)
+			o.jobs = append(o.jobs, &lintJob{test: test, path: path})
 		}
 		if r.Agent == "" && r.PipelineRunSpec != nil {
 			r.Agent = job.TektonPipelineAgent
@@ -163,10 +243,8 @@ func (o *Options) loadConfigFile(repoConfig *triggerconfig.Config, dir string) *
 				File: path,
 			}
 			o.Tests = append(o.Tests, test)
-			err := loadJobBaseFromSourcePath(ctx, path)
-			if err != nil {
-				test.Error = err
-			}
+			o.recordSourceLocation(path)
+			o.jobs = append(o.jobs, &lintJob{test: test, path: path})
 		}
 		if r.Agent == "" && r.PipelineRunSpec != nil {
 			r.Agent = job.TektonPipelineAgent
@@ -175,13 +253,96 @@ func (o *Options) loadConfigFile(repoConfig *triggerconfig.Config, dir string) *
 	return repoConfig
 }
 
-func loadJobBaseFromSourcePath(ctx context.Context, path string) error {
+// runJobs validates every discovered source file on a worker pool bounded by o.Concurrency,
+// each with its own o.Timeout context, so a recursive lint across hundreds of catalog files
+// doesn't pay for full Tekton validation sequentially. o.Tests itself is never mutated while
+// the pool is running - each job only ever writes to the single *linter.Test it owns, and any
+// policy violations it finds are collected into its own j.policyTests - so the flatten below
+// appends them in job discovery order once every job has finished, regardless of which one
+// happened to finish first, and LogResults/WriteSarifReport render deterministically.
+func (o *Options) runJobs() {
+	if len(o.jobs) == 0 {
+		return
+	}
+
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	parentCtx := o.GetContext()
+
+	jobsCh := make(chan *lintJob)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobsCh {
+				o.runJob(parentCtx, j)
+			}
+		}()
+	}
+	for _, j := range o.jobs {
+		jobsCh <- j
+	}
+	close(jobsCh)
+	wg.Wait()
+
+	for _, j := range o.jobs {
+		o.Tests = append(o.Tests, j.policyTests...)
+	}
+}
+
+func (o *Options) runJob(parentCtx context.Context, j *lintJob) {
+	ctx := parentCtx
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parentCtx, o.Timeout)
+		defer cancel()
+	}
+	policyTests, err := o.loadJobBaseFromSourcePath(ctx, j.path)
+	j.policyTests = policyTests
+	if err != nil {
+		j.test.Error = err
+	}
+}
+
+// evaluatePolicy evaluates input against the --policy-dir Rego policies, if configured,
+// returning one linter.Test per violation so the caller can fold them into o.Tests in
+// whatever order it needs - the evaluator itself has no ordering requirements.
+func (o *Options) evaluatePolicy(ctx context.Context, file string, input interface{}) []*linter.Test {
+	if o.policyEvaluator == nil {
+		return nil
+	}
+	tests, err := o.policyEvaluator.Tests(ctx, file, input)
+	if err != nil {
+		return []*linter.Test{{File: file, Error: err}}
+	}
+	return tests
+}
+
+// recordSourceLocation remembers the line/column of a source file's root YAML node, so a
+// failed lint test can later be reported against a real location instead of just a file name.
+func (o *Options) recordSourceLocation(path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil || len(node.Content) == 0 {
+		return
+	}
+	root := node.Content[0]
+	o.sourceLocations[path] = sourceLocation{Line: root.Line, Column: root.Column}
+}
+
+func (o *Options) loadJobBaseFromSourcePath(ctx context.Context, path string) ([]*linter.Test, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return errors.Wrapf(err, "failed to load file %s", path)
+		return nil, errors.Wrapf(err, "failed to load file %s", path)
 	}
 	if len(data) == 0 {
-		return errors.Errorf("empty file file %s", path)
+		return nil, errors.Errorf("empty file file %s", path)
 	}
 
 	dir := filepath.Dir(path)
@@ -197,12 +358,13 @@ func loadJobBaseFromSourcePath(ctx context.Context, path string) error {
 
 	pr, err := inrepo.LoadTektonResourceAsPipelineRun(data, dir, message, getData, nil)
 	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal YAML file %s", path)
+		return nil, errors.Wrapf(err, "failed to unmarshal YAML file %s", path)
 	}
+	policyTests := o.evaluatePolicy(ctx, path, pr)
 
 	fieldError := pr.Validate(ctx)
 	if fieldError != nil {
-		return errors.Wrapf(fieldError, "failed to validate YAML file %s", path)
+		return policyTests, errors.Wrapf(fieldError, "failed to validate YAML file %s", path)
 	}
-	return nil
+	return policyTests, nil
 }
@@ -0,0 +1,183 @@
+package lint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/linter"
+	"github.com/pkg/errors"
+)
+
+const (
+	sarifVersion         = "2.1.0"
+	sarifSchema          = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+	sarifToolName        = "jx-pipeline-lint"
+	sarifToolInfoURI     = "https://github.com/jenkins-x/jx-pipeline"
+	ruleEmptySource      = "empty-source-file"
+	ruleTriggersMissing  = "triggers-missing"
+	ruleInvalidPipeline  = "invalid-pipelinerun"
+	rulePolicyPrivileged = "policy-privileged-container"
+	rulePolicyResources  = "policy-missing-resource-limits"
+	rulePolicyLatestTag  = "policy-latest-image-tag"
+	rulePolicyNonRoot    = "policy-missing-run-as-non-root"
+)
+
+// sarifLog is the root of a SARIF 2.1.0 log
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+var sarifRules = []sarifRule{
+	{ID: ruleEmptySource, ShortDescription: sarifMessage{Text: "Pipeline source file is empty"}},
+	{ID: ruleTriggersMissing, ShortDescription: sarifMessage{Text: "Failed to load or parse triggers.yaml"}},
+	{ID: ruleInvalidPipeline, ShortDescription: sarifMessage{Text: "PipelineRun failed Tekton validation"}},
+	{ID: rulePolicyPrivileged, ShortDescription: sarifMessage{Text: "Container must not run as privileged (policy)"}},
+	{ID: rulePolicyResources, ShortDescription: sarifMessage{Text: "Container must set resources.limits (policy)"}},
+	{ID: rulePolicyLatestTag, ShortDescription: sarifMessage{Text: "Container must not use the :latest image tag (policy)"}},
+	{ID: rulePolicyNonRoot, ShortDescription: sarifMessage{Text: "Container must set securityContext.runAsNonRoot (policy)"}},
+}
+
+// ruleIDForTest classifies a failed linter.Test into a SARIF rule id based on the file
+// it was linting and the wrapped error message, so results can be grouped by check. The
+// policy.Evaluator wraps each Rego `deny` message verbatim (see builtin/containers.rego),
+// so its wording is matched here to keep the four built-in policy checks distinctly tagged
+// instead of collapsing them into the generic invalid-pipelinerun rule.
+func ruleIDForTest(test *linter.Test) string {
+	if test.Error == nil {
+		return ""
+	}
+	message := test.Error.Error()
+	switch {
+	case strings.Contains(message, "empty file"):
+		return ruleEmptySource
+	case strings.Contains(message, "failed to unmarshal YAML"), strings.Contains(message, "failed to validate YAML"):
+		return ruleInvalidPipeline
+	case strings.HasSuffix(test.File, "triggers.yaml"):
+		return ruleTriggersMissing
+	case strings.Contains(message, "must not run as privileged"):
+		return rulePolicyPrivileged
+	case strings.Contains(message, "must set resources.limits"):
+		return rulePolicyResources
+	case strings.Contains(message, "must not use the :latest image tag"):
+		return rulePolicyLatestTag
+	case strings.Contains(message, "must set securityContext.runAsNonRoot"):
+		return rulePolicyNonRoot
+	default:
+		return ruleInvalidPipeline
+	}
+}
+
+// locationForTest returns the best known line/column for a failed test, falling back to
+// line 1 when the source file was never parsed into a yaml.Node (e.g. it failed to read).
+func (o *Options) locationForTest(test *linter.Test) (int, int) {
+	loc, ok := o.sourceLocations[test.File]
+	if !ok {
+		return 1, 1
+	}
+	return loc.Line, loc.Column
+}
+
+// WriteSarifReport converts the failed linter.Tests into a SARIF 2.1.0 report and writes it to outFile,
+// so results can be uploaded to GitHub code scanning or similar consumers.
+func (o *Options) WriteSarifReport(tests []*linter.Test, outFile string) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           sarifToolName,
+				InformationURI: sarifToolInfoURI,
+				Rules:          sarifRules,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if test.Error == nil {
+			continue
+		}
+		line, column := o.locationForTest(test)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  ruleIDForTest(test),
+			Level:   "error",
+			Message: sarifMessage{Text: test.Error.Error()},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: test.File},
+						Region: sarifRegion{
+							StartLine:   line,
+							StartColumn: column,
+						},
+					},
+				},
+			},
+		})
+	}
+
+	report := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+
+	data, err := json.MarshalIndent(&report, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal SARIF report")
+	}
+	err = ioutil.WriteFile(outFile, data, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write SARIF report to %s", outFile)
+	}
+	return nil
+}
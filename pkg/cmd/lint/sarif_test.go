@@ -0,0 +1,34 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/linter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleIDForTest(t *testing.T) {
+	tests := []struct {
+		name   string
+		test   *linter.Test
+		ruleID string
+	}{
+		{"no error", &linter.Test{File: "pr.yaml"}, ""},
+		{"empty file", &linter.Test{File: "pr.yaml", Error: errors.New("empty file file pr.yaml")}, ruleEmptySource},
+		{"unmarshal failure", &linter.Test{File: "pr.yaml", Error: errors.New("failed to unmarshal YAML file pr.yaml: bang")}, ruleInvalidPipeline},
+		{"validate failure", &linter.Test{File: "pr.yaml", Error: errors.New("failed to validate YAML file pr.yaml: bang")}, ruleInvalidPipeline},
+		{"triggers file", &linter.Test{File: ".lighthouse/jenkins-x/triggers.yaml", Error: errors.New("bang")}, ruleTriggersMissing},
+		{"privileged container policy", &linter.Test{File: "pr.yaml", Error: errors.New(`container "build" must not run as privileged`)}, rulePolicyPrivileged},
+		{"missing resource limits policy", &linter.Test{File: "pr.yaml", Error: errors.New(`container "build" must set resources.limits`)}, rulePolicyResources},
+		{"latest image tag policy", &linter.Test{File: "pr.yaml", Error: errors.New(`container "build" must not use the :latest image tag`)}, rulePolicyLatestTag},
+		{"missing runAsNonRoot policy", &linter.Test{File: "pr.yaml", Error: errors.New(`container "build" must set securityContext.runAsNonRoot`)}, rulePolicyNonRoot},
+		{"unrecognised error falls back", &linter.Test{File: "pr.yaml", Error: errors.New("something else went wrong")}, ruleInvalidPipeline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ruleID, ruleIDForTest(tt.test))
+		})
+	}
+}
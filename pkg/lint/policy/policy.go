@@ -0,0 +1,66 @@
+// Package policy evaluates lighthouse trigger configs and resolved Tekton PipelineRuns
+// against Rego policies, following the convention that a ruleset sets data.jxpipeline.deny
+// to the set of violation messages for a given input.
+package policy
+
+import (
+	"context"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/linter"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/pkg/errors"
+)
+
+// denyQuery is the rego convention this linter expects every policy to follow.
+const denyQuery = "data.jxpipeline.deny"
+
+// Evaluator evaluates lighthouse trigger configs and PipelineRuns against a directory of
+// Rego policies, turning every `deny` message into an additional linter.Test failure so
+// platform teams can centrally enforce org policies without hard-coding rules in Go.
+type Evaluator struct {
+	query rego.PreparedEvalQuery
+}
+
+// NewEvaluator compiles every *.rego file under dir into a single evaluator.
+func NewEvaluator(ctx context.Context, dir string) (*Evaluator, error) {
+	r := rego.New(
+		rego.Query(denyQuery),
+		rego.Load([]string{dir}, nil),
+	)
+	query, err := r.PrepareForEval(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compile policies in %s", dir)
+	}
+	return &Evaluator{query: query}, nil
+}
+
+// Tests evaluates input (a triggerconfig.Config or a resolved PipelineRun) against the
+// compiled policies and returns one linter.Test per violation, so they flow through the same
+// reporting (terminal output or --format sarif) as the existing Tekton validation checks.
+func (e *Evaluator) Tests(ctx context.Context, file string, input interface{}) ([]*linter.Test, error) {
+	results, err := e.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate policy against %s", file)
+	}
+
+	var tests []*linter.Test
+	for _, result := range results {
+		for _, expr := range result.Expressions {
+			messages, ok := expr.Value.([]interface{})
+			if !ok {
+				continue
+			}
+			for _, m := range messages {
+				msg, ok := m.(string)
+				if !ok || msg == "" {
+					continue
+				}
+				tests = append(tests, &linter.Test{
+					File:  file,
+					Error: errors.New(msg),
+				})
+			}
+		}
+	}
+	return tests, nil
+}
@@ -0,0 +1,90 @@
+package policy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x/jx-pipeline/pkg/lint/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluatorTestsAgainstBuiltinContainersPolicy(t *testing.T) {
+	evaluator, err := policy.NewEvaluator(context.Background(), "builtin")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"pipelineSpec": map[string]interface{}{
+				"tasks": []interface{}{
+					map[string]interface{}{
+						"taskSpec": map[string]interface{}{
+							"steps": []interface{}{
+								map[string]interface{}{
+									"name":  "build",
+									"image": "gcr.io/example/build:latest",
+									"securityContext": map[string]interface{}{
+										"privileged": true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests, err := evaluator.Tests(context.Background(), "pr.yaml", input)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, test := range tests {
+		assert.Equal(t, "pr.yaml", test.File)
+		require.Error(t, test.Error)
+		messages = append(messages, test.Error.Error())
+	}
+
+	assert.ElementsMatch(t, []string{
+		`container "build" must not run as privileged`,
+		`container "build" must set resources.limits`,
+		`container "build" must not use the :latest image tag`,
+		`container "build" must set securityContext.runAsNonRoot`,
+	}, messages)
+}
+
+func TestEvaluatorTestsNoViolations(t *testing.T) {
+	evaluator, err := policy.NewEvaluator(context.Background(), "builtin")
+	require.NoError(t, err)
+
+	input := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"pipelineSpec": map[string]interface{}{
+				"tasks": []interface{}{
+					map[string]interface{}{
+						"taskSpec": map[string]interface{}{
+							"steps": []interface{}{
+								map[string]interface{}{
+									"name":  "build",
+									"image": "gcr.io/example/build:1.2.3",
+									"securityContext": map[string]interface{}{
+										"runAsNonRoot": true,
+									},
+									"resources": map[string]interface{}{
+										"limits": map[string]interface{}{
+											"cpu": "1",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests, err := evaluator.Tests(context.Background(), "pr.yaml", input)
+	require.NoError(t, err)
+	assert.Empty(t, tests)
+}
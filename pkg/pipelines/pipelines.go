@@ -9,6 +9,7 @@ import (
 	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/activities"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/naming"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -90,13 +91,27 @@ func DefaultValues(a *v1.PipelineActivity) {
 	}
 }
 
-// ToPipelineActivityName creates an activity name from a pipeline run
+// ToPipelineActivityName creates an activity name from a v1beta1 PipelineRun. See
+// ToPipelineActivityNameV1 for the Tekton v1 equivalent.
 func ToPipelineActivityName(pr *v1beta1.PipelineRun, paList []v1.PipelineActivity) string {
-	labels := pr.Labels
-	if labels == nil {
+	if pr.Labels == nil {
 		return ""
 	}
+	return activityNameFromLabels(pr.Labels, paList)
+}
 
+// ToPipelineActivityNameV1 creates an activity name from a Tekton v1 PipelineRun.
+func ToPipelineActivityNameV1(pr *pipelinev1.PipelineRun, paList []v1.PipelineActivity) string {
+	if pr.Labels == nil {
+		return ""
+	}
+	return activityNameFromLabels(pr.Labels, paList)
+}
+
+// activityNameFromLabels is the version-agnostic core of ToPipelineActivityName/
+// ToPipelineActivityNameV1. It mutates labels in place to record the build number it resolved,
+// which works for both Tekton API versions since labels is always the PipelineRun's own map.
+func activityNameFromLabels(labels map[string]string, paList []v1.PipelineActivity) string {
 	build := labels["build"]
 	owner := GetLabel(labels, OwnerLabels)
 	repository := GetLabel(labels, RepoLabels)
@@ -119,7 +134,7 @@ func ToPipelineActivityName(pr *v1beta1.PipelineRun, paList []v1.PipelineActivit
 			}
 			if pa.Labels["buildID"] == buildID || pa.Labels["lighthouse.jenkins-x.io/buildNum"] == buildID {
 				if pa.Spec.Build != "" {
-					pr.Labels["build"] = pa.Spec.Build
+					labels["build"] = pa.Spec.Build
 					return pa.Name
 				}
 			}
@@ -139,7 +154,7 @@ func ToPipelineActivityName(pr *v1beta1.PipelineRun, paList []v1.PipelineActivit
 				}
 			}
 			if !found {
-				pr.Labels["build"] = build
+				labels["build"] = build
 				return name
 			}
 			b++
@@ -151,16 +166,39 @@ func ToPipelineActivityName(pr *v1beta1.PipelineRun, paList []v1.PipelineActivit
 	return naming.ToValidName(prefix + build)
 }
 
+// ToPipelineActivity populates pa from a Tekton v1beta1 PipelineRun. See ToPipelineActivityV1
+// for the Tekton v1 equivalent.
 func ToPipelineActivity(pr *v1beta1.PipelineRun, pa *v1.PipelineActivity, overwriteSteps bool) {
-	annotations := pr.Annotations
-	labels := pr.Labels
+	toPipelineActivity(v1beta1Adapter{pr}, pr, pa, overwriteSteps)
+}
+
+// ToPipelineActivityV1 populates pa from a Tekton v1 PipelineRun. Tekton v1's child-reference
+// model means the PipelineRun no longer carries its TaskRuns' status inline the way v1beta1
+// did, so getTaskRun is used to resolve each one; pass nil if the PipelineRun's
+// Status.ChildReferences should simply be skipped (e.g. they haven't started yet).
+func ToPipelineActivityV1(pr *pipelinev1.PipelineRun, pa *v1.PipelineActivity, overwriteSteps bool, getTaskRun TaskRunGetter) error {
+	adapter := v1Adapter{pr: pr, getTaskRun: getTaskRun}
+	taskRuns, err := adapter.resolveTaskRuns()
+	if err != nil {
+		return err
+	}
+	toPipelineActivity(resolvedV1Adapter{v1Adapter: adapter, taskRuns: taskRuns}, nil, pa, overwriteSteps)
+	return nil
+}
+
+// toPipelineActivity is the version-agnostic core of ToPipelineActivity/ToPipelineActivityV1.
+// traceSource is only non-nil for the v1beta1 entry point, since Tracer is currently defined in
+// terms of *v1beta1.PipelineRun; the v1 path emits no spans until Tracer grows a v1 overload.
+func toPipelineActivity(pr pipelineRunLike, traceSource *v1beta1.PipelineRun, pa *v1.PipelineActivity, overwriteSteps bool) {
+	annotations := pr.GetAnnotations()
+	labels := pr.GetLabels()
 	if pa.APIVersion == "" {
 		pa.APIVersion = "jenkins.io/v1"
 	}
 	if pa.Kind == "" {
 		pa.Kind = "PipelineActivity"
 	}
-	pa.Namespace = pr.Namespace
+	pa.Namespace = pr.GetNamespace()
 
 	if pa.Annotations == nil {
 		pa.Annotations = map[string]string{}
@@ -216,8 +254,9 @@ func ToPipelineActivity(pr *v1beta1.PipelineRun, pa *v1.PipelineActivity, overwr
 	podName := ""
 	stageNames := map[string]bool{}
 	var steps []v1.PipelineActivityStep
-	if pr.Status.TaskRuns != nil {
-		for _, v := range pr.Status.TaskRuns {
+	taskRuns := pr.TaskRuns()
+	if taskRuns != nil {
+		for _, v := range taskRuns {
 			stageName := strings.ReplaceAll(v.PipelineTaskName, "-", " ")
 			stageNames[stageName] = true
 			var stage *v1.PipelineActivityStep
@@ -283,6 +322,64 @@ func ToPipelineActivity(pr *v1beta1.PipelineRun, pa *v1.PipelineActivity, overwr
 				}
 				stage.Stage.Steps = append(stage.Stage.Steps, step)
 			}
+
+			sidecarFailed := false
+			for _, sidecar := range v.Status.Sidecars {
+				name := sidecar.Name
+				var started *metav1.Time
+				var completed *metav1.Time
+				status := v1.ActivityStatusTypePending
+
+				terminated := sidecar.Terminated
+				if terminated != nil {
+					if terminated.ExitCode == 0 {
+						status = v1.ActivityStatusTypeSucceeded
+					} else if !terminated.FinishedAt.IsZero() {
+						status = v1.ActivityStatusTypeFailed
+						sidecarFailed = true
+					}
+					started = &terminated.StartedAt
+					completed = &terminated.FinishedAt
+				} else if sidecar.Running != nil {
+					started = &sidecar.Running.StartedAt
+					status = v1.ActivityStatusTypeRunning
+				}
+
+				if status.IsTerminated() && completed == nil {
+					completed = &metav1.Time{
+						Time: time.Now(),
+					}
+				}
+
+				sidecarStep := v1.CoreActivityStep{
+					Name:               "Sidecar: " + Humanize(name),
+					Description:        "",
+					Status:             status,
+					StartedTimestamp:   started,
+					CompletedTimestamp: completed,
+				}
+
+				if stage == nil {
+					stage = &v1.PipelineActivityStep{
+						Kind: v1.ActivityStepKindTypeStage,
+						Stage: &v1.StageActivityStep{
+							CoreActivityStep: v1.CoreActivityStep{
+								Name:             stageName,
+								Description:      "",
+								Status:           status,
+								StartedTimestamp: started,
+							},
+						},
+					}
+				}
+				stage.Stage.Steps = append(stage.Stage.Steps, sidecarStep)
+			}
+			// a failed sidecar (e.g. a dind/registry sidecar dying mid-build) should fail the
+			// whole stage even though every step it ran alongside may have succeeded
+			if sidecarFailed && stage != nil {
+				stage.Stage.Status = v1.ActivityStatusTypeFailed
+			}
+
 			if stage != nil {
 				// lets check we have a started time if we have at least 1 step
 				if stage.Stage != nil && len(stage.Stage.Steps) > 0 {
@@ -388,6 +485,10 @@ func ToPipelineActivity(pr *v1beta1.PipelineRun, pa *v1.PipelineActivity, overwr
 		pa.Labels["podName"] = podName
 	}
 
+	if traceSource != nil {
+		tracer.Trace(traceSource, pa, stageSpansFromSteps(steps))
+	}
+
 	activities.UpdateStatus(pa, false, nil)
 }
 
@@ -0,0 +1,68 @@
+package pipelines_test
+
+import (
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-pipeline/pkg/pipelines"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToPipelineActivitySidecarFailureFailsStage(t *testing.T) {
+	pr := &v1beta1.PipelineRun{
+		Status: v1beta1.PipelineRunStatus{
+			PipelineRunStatusFields: v1beta1.PipelineRunStatusFields{
+				TaskRuns: map[string]*v1beta1.PipelineRunTaskRunStatus{
+					"build-task-run": {
+						PipelineTaskName: "build",
+						Status: &v1beta1.TaskRunStatus{
+							TaskRunStatusFields: v1beta1.TaskRunStatusFields{
+								PodName: "build-pod",
+								Steps: []v1beta1.StepState{
+									{
+										Name: "build",
+										ContainerState: corev1.ContainerState{
+											Terminated: &corev1.ContainerStateTerminated{
+												ExitCode:   0,
+												StartedAt:  metav1.Now(),
+												FinishedAt: metav1.Now(),
+											},
+										},
+									},
+								},
+								Sidecars: []v1beta1.SidecarState{
+									{
+										Name: "dind",
+										ContainerState: corev1.ContainerState{
+											Terminated: &corev1.ContainerStateTerminated{
+												ExitCode:   1,
+												StartedAt:  metav1.Now(),
+												FinishedAt: metav1.Now(),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	pa := &v1.PipelineActivity{}
+	pipelines.ToPipelineActivity(pr, pa, false)
+
+	assert.Len(t, pa.Spec.Steps, 1, "expected a single stage")
+	stage := pa.Spec.Steps[0].Stage
+	if assert.NotNil(t, stage, "expected a stage") {
+		assert.Equal(t, v1.ActivityStatusTypeFailed, stage.Status, "stage status should fail when a sidecar fails")
+		assert.Len(t, stage.Steps, 2, "expected a step and a sidecar step")
+		assert.Equal(t, "Build", stage.Steps[0].Name)
+		assert.Equal(t, "Sidecar: Dind", stage.Steps[1].Name)
+		assert.Equal(t, v1.ActivityStatusTypeFailed, stage.Steps[1].Status)
+	}
+}
@@ -0,0 +1,162 @@
+package pipelines
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	traceparentAnnotation = "lighthouse.jenkins-x.io/traceparent"
+	tracestateAnnotation  = "lighthouse.jenkins-x.io/tracestate"
+	tracerName            = "github.com/jenkins-x/jx-pipeline/pkg/pipelines"
+)
+
+// StageSpan describes a single pipeline stage and its steps, in the shape Tracer needs to emit
+// spans - decoupled from v1.PipelineActivityStep so Tracer doesn't need to know how activities
+// are modelled.
+type StageSpan struct {
+	Name      string
+	Status    v1.ActivityStatusType
+	Started   *metav1.Time
+	Completed *metav1.Time
+	Steps     []StepSpan
+}
+
+// StepSpan describes a single step within a StageSpan.
+type StepSpan struct {
+	Name      string
+	Status    v1.ActivityStatusType
+	Started   *metav1.Time
+	Completed *metav1.Time
+}
+
+// Tracer emits OpenTelemetry spans for a PipelineRun's stages and steps. The default is a
+// no-op so callers that haven't configured OpenTelemetry see no behaviour change.
+type Tracer interface {
+	Trace(pr *v1beta1.PipelineRun, pa *v1.PipelineActivity, stages []StageSpan)
+}
+
+// noopTracer is the default Tracer: it does nothing.
+type noopTracer struct{}
+
+func (noopTracer) Trace(*v1beta1.PipelineRun, *v1.PipelineActivity, []StageSpan) {}
+
+// tracer is the package-level Tracer used by ToPipelineActivity. Override it with SetTracer.
+var tracer Tracer = noopTracer{}
+
+// SetTracer overrides the package-level Tracer used by ToPipelineActivity. Pass nil to restore
+// the no-op default.
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = noopTracer{}
+	}
+	tracer = t
+}
+
+// otelTracer emits real OpenTelemetry spans, parented on the trace context extracted from the
+// PipelineRun's lighthouse traceparent/tracestate annotations. A PipelineRun without a
+// traceparent annotation gets no spans at all.
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+// NewOtelTracer returns a Tracer that emits one span per stage and one child span per step,
+// using go.opentelemetry.io/otel's globally configured TracerProvider, so a whole PipelineRun
+// can be visualised in Jaeger/Tempo alongside the webhook span lighthouse already starts.
+func NewOtelTracer() Tracer {
+	return &otelTracer{tracer: otel.Tracer(tracerName)}
+}
+
+func (t *otelTracer) Trace(pr *v1beta1.PipelineRun, pa *v1.PipelineActivity, stages []StageSpan) {
+	traceparent := pr.Annotations[traceparentAnnotation]
+	if traceparent == "" || len(stages) == 0 {
+		return
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceparent}
+	if tracestate := pr.Annotations[tracestateAnnotation]; tracestate != "" {
+		carrier["tracestate"] = tracestate
+	}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), carrier)
+
+	rootStart := time.Now()
+	rootEnd := rootStart
+	if stages[0].Started != nil {
+		rootStart = stages[0].Started.Time
+	}
+	if last := stages[len(stages)-1]; last.Completed != nil {
+		rootEnd = last.Completed.Time
+	}
+
+	ctx, rootSpan := t.tracer.Start(ctx, pa.Spec.Pipeline, trace.WithTimestamp(rootStart))
+	rootSpan.SetAttributes(
+		attribute.String("owner", pa.Spec.GitOwner),
+		attribute.String("repo", pa.Spec.GitRepository),
+		attribute.String("branch", pa.Spec.GitBranch),
+		attribute.String("build", pa.Spec.Build),
+		attribute.String("status", string(pa.Spec.Status)),
+	)
+	defer rootSpan.End(trace.WithTimestamp(rootEnd))
+
+	for _, stage := range stages {
+		stageStart := rootStart
+		if stage.Started != nil {
+			stageStart = stage.Started.Time
+		}
+		stageEnd := rootEnd
+		if stage.Completed != nil {
+			stageEnd = stage.Completed.Time
+		}
+		stageCtx, stageSpan := t.tracer.Start(ctx, stage.Name, trace.WithTimestamp(stageStart))
+		stageSpan.SetAttributes(attribute.String("status", string(stage.Status)))
+
+		for _, step := range stage.Steps {
+			stepStart := stageStart
+			if step.Started != nil {
+				stepStart = step.Started.Time
+			}
+			stepEnd := stageEnd
+			if step.Completed != nil {
+				stepEnd = step.Completed.Time
+			}
+			_, stepSpan := t.tracer.Start(stageCtx, step.Name, trace.WithTimestamp(stepStart))
+			stepSpan.SetAttributes(attribute.String("status", string(step.Status)))
+			stepSpan.End(trace.WithTimestamp(stepEnd))
+		}
+		stageSpan.End(trace.WithTimestamp(stageEnd))
+	}
+}
+
+// stageSpansFromSteps converts the stage/step model built while walking a PipelineRun's
+// TaskRuns into the Tracer's StageSpan shape.
+func stageSpansFromSteps(steps []v1.PipelineActivityStep) []StageSpan {
+	var spans []StageSpan
+	for _, s := range steps {
+		if s.Stage == nil {
+			continue
+		}
+		span := StageSpan{
+			Name:      s.Stage.Name,
+			Status:    s.Stage.Status,
+			Started:   s.Stage.StartedTimestamp,
+			Completed: s.Stage.CompletedTimestamp,
+		}
+		for _, step := range s.Stage.Steps {
+			span.Steps = append(span.Steps, StepSpan{
+				Name:      step.Name,
+				Status:    step.Status,
+				Started:   step.StartedTimestamp,
+				Completed: step.CompletedTimestamp,
+			})
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
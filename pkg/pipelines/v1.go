@@ -0,0 +1,119 @@
+package pipelines
+
+import (
+	"github.com/pkg/errors"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// TaskRunGetter looks up a single Tekton v1 TaskRun by name/namespace. Tekton v1's
+// PipelineRun.Status only carries ChildReferences (a name plus the PipelineTask it belongs to)
+// rather than the TaskRun status inline the way v1beta1 always did, so ToPipelineActivityV1
+// needs this callback to resolve each child into its actual status.
+type TaskRunGetter func(name, namespace string) (*pipelinev1.TaskRun, error)
+
+// pipelineRunLike abstracts over v1beta1.PipelineRun and v1.PipelineRun so the conversion in
+// toPipelineActivity only needs to be written once, with one adapter per Tekton API version.
+type pipelineRunLike interface {
+	GetNamespace() string
+	GetLabels() map[string]string
+	GetAnnotations() map[string]string
+	TaskRuns() map[string]*v1beta1.PipelineRunTaskRunStatus
+}
+
+// v1beta1Adapter adapts a v1beta1.PipelineRun - it has always carried its TaskRuns' status
+// inline, so there's nothing to resolve.
+type v1beta1Adapter struct {
+	pr *v1beta1.PipelineRun
+}
+
+func (a v1beta1Adapter) GetNamespace() string {
+	return a.pr.Namespace
+}
+
+func (a v1beta1Adapter) GetLabels() map[string]string {
+	return a.pr.Labels
+}
+
+func (a v1beta1Adapter) GetAnnotations() map[string]string {
+	return a.pr.Annotations
+}
+func (a v1beta1Adapter) TaskRuns() map[string]*v1beta1.PipelineRunTaskRunStatus {
+	return a.pr.Status.TaskRuns
+}
+
+// v1Adapter adapts a v1.PipelineRun, resolving its ChildReferences against the injected
+// TaskRunGetter into the same v1beta1.PipelineRunTaskRunStatus shape the core conversion
+// already understands.
+type v1Adapter struct {
+	pr         *pipelinev1.PipelineRun
+	getTaskRun TaskRunGetter
+}
+
+func (a v1Adapter) GetNamespace() string {
+	return a.pr.Namespace
+}
+
+func (a v1Adapter) GetLabels() map[string]string {
+	return a.pr.Labels
+}
+
+func (a v1Adapter) GetAnnotations() map[string]string {
+	return a.pr.Annotations
+}
+
+// resolveTaskRuns fetches every child TaskRun referenced by the PipelineRun and converts it
+// into a v1beta1.PipelineRunTaskRunStatus, so it can be fed through the same conversion logic
+// v1beta1.PipelineRun.Status.TaskRuns always fed directly.
+func (a v1Adapter) resolveTaskRuns() (map[string]*v1beta1.PipelineRunTaskRunStatus, error) {
+	statuses := map[string]*v1beta1.PipelineRunTaskRunStatus{}
+	if a.getTaskRun == nil {
+		return statuses, nil
+	}
+	for _, child := range a.pr.Status.ChildReferences {
+		tr, err := a.getTaskRun(child.Name, a.pr.Namespace)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get TaskRun %s", child.Name)
+		}
+		if tr == nil {
+			continue
+		}
+		statuses[child.Name] = &v1beta1.PipelineRunTaskRunStatus{
+			PipelineTaskName: child.PipelineTaskName,
+			Status:           convertTaskRunStatus(tr),
+		}
+	}
+	return statuses, nil
+}
+
+func convertTaskRunStatus(tr *pipelinev1.TaskRun) *v1beta1.TaskRunStatus {
+	status := &v1beta1.TaskRunStatus{
+		TaskRunStatusFields: v1beta1.TaskRunStatusFields{
+			PodName: tr.Status.PodName,
+		},
+	}
+	for _, s := range tr.Status.Steps {
+		status.Steps = append(status.Steps, v1beta1.StepState{
+			Name:           s.Name,
+			ContainerState: s.ContainerState,
+		})
+	}
+	for _, s := range tr.Status.Sidecars {
+		status.Sidecars = append(status.Sidecars, v1beta1.SidecarState{
+			Name:           s.Name,
+			ContainerState: s.ContainerState,
+		})
+	}
+	return status
+}
+
+// resolvedV1Adapter is a pipelineRunLike over a v1.PipelineRun whose child TaskRuns have
+// already been resolved, so toPipelineActivity never has to know a lookup happened at all.
+type resolvedV1Adapter struct {
+	v1Adapter
+	taskRuns map[string]*v1beta1.PipelineRunTaskRunStatus
+}
+
+func (a resolvedV1Adapter) TaskRuns() map[string]*v1beta1.PipelineRunTaskRunStatus {
+	return a.taskRuns
+}
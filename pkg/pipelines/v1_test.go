@@ -0,0 +1,93 @@
+package pipelines_test
+
+import (
+	"testing"
+
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-pipeline/pkg/pipelines"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newV1PipelineRun(childName, pipelineTaskName string) *pipelinev1.PipelineRun {
+	return &pipelinev1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "jx"},
+		Status: pipelinev1.PipelineRunStatus{
+			PipelineRunStatusFields: pipelinev1.PipelineRunStatusFields{
+				ChildReferences: []pipelinev1.ChildStatusReference{
+					{Name: childName, PipelineTaskName: pipelineTaskName},
+				},
+			},
+		},
+	}
+}
+
+func TestToPipelineActivityV1ResolvesChildTaskRun(t *testing.T) {
+	pr := newV1PipelineRun("build-task-run", "build")
+
+	getTaskRun := func(name, namespace string) (*pipelinev1.TaskRun, error) {
+		assert.Equal(t, "build-task-run", name)
+		assert.Equal(t, "jx", namespace)
+		return &pipelinev1.TaskRun{
+			Status: pipelinev1.TaskRunStatus{
+				TaskRunStatusFields: pipelinev1.TaskRunStatusFields{
+					PodName: "build-pod",
+					Steps: []pipelinev1.StepState{
+						{
+							Name: "build",
+							ContainerState: corev1.ContainerState{
+								Terminated: &corev1.ContainerStateTerminated{
+									ExitCode:   0,
+									StartedAt:  metav1.Now(),
+									FinishedAt: metav1.Now(),
+								},
+							},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	pa := &v1.PipelineActivity{}
+	err := pipelines.ToPipelineActivityV1(pr, pa, false, getTaskRun)
+	require.NoError(t, err)
+
+	require.Len(t, pa.Spec.Steps, 1)
+	stage := pa.Spec.Steps[0].Stage
+	require.NotNil(t, stage)
+	assert.Equal(t, "build", stage.Name)
+	require.Len(t, stage.Steps, 1)
+	assert.Equal(t, "Build", stage.Steps[0].Name)
+	assert.Equal(t, v1.ActivityStatusTypeSucceeded, stage.Steps[0].Status)
+}
+
+func TestToPipelineActivityV1PropagatesTaskRunGetterError(t *testing.T) {
+	pr := newV1PipelineRun("build-task-run", "build")
+
+	getTaskRun := func(name, namespace string) (*pipelinev1.TaskRun, error) {
+		return nil, errors.New("boom")
+	}
+
+	pa := &v1.PipelineActivity{}
+	err := pipelines.ToPipelineActivityV1(pr, pa, false, getTaskRun)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "build-task-run")
+}
+
+func TestToPipelineActivityV1SkipsNilTaskRun(t *testing.T) {
+	pr := newV1PipelineRun("build-task-run", "build")
+
+	getTaskRun := func(name, namespace string) (*pipelinev1.TaskRun, error) {
+		return nil, nil
+	}
+
+	pa := &v1.PipelineActivity{}
+	err := pipelines.ToPipelineActivityV1(pr, pa, false, getTaskRun)
+	require.NoError(t, err)
+	assert.Empty(t, pa.Spec.Steps, "a skipped child TaskRun should produce no stage")
+}